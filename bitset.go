@@ -0,0 +1,67 @@
+package bloomfilter
+
+import "math/bits"
+
+// bitset is a packed array of bits backed by 64-bit words, used as the
+// storage for BloomFilter. It is roughly 8x more memory-efficient than a
+// []bool of the same length, since Go allocates a full byte per bool.
+type bitset struct {
+	words []uint64
+	nbits uint
+}
+
+// newBitset returns a bitset large enough to hold nbits bits, all clear.
+func newBitset(nbits uint) *bitset {
+	return &bitset{
+		words: make([]uint64, (nbits+63)/64),
+		nbits: nbits,
+	}
+}
+
+// set sets the bit at index i.
+func (b *bitset) set(i uint) {
+	b.words[i/64] |= 1 << (i % 64)
+}
+
+// get reports whether the bit at index i is set.
+func (b *bitset) get(i uint) bool {
+	return b.words[i/64]&(1<<(i%64)) != 0
+}
+
+// count returns the number of set bits (population count).
+func (b *bitset) count() uint {
+	var c uint
+	for _, w := range b.words {
+		c += uint(bits.OnesCount64(w))
+	}
+	return c
+}
+
+// union ORs other into b in place. Callers must ensure b and other have
+// the same number of words.
+func (b *bitset) union(other *bitset) {
+	for i, w := range other.words {
+		b.words[i] |= w
+	}
+}
+
+// intersect ANDs other into b in place. Callers must ensure b and other
+// have the same number of words.
+func (b *bitset) intersect(other *bitset) {
+	for i, w := range other.words {
+		b.words[i] &= w
+	}
+}
+
+// equals reports whether b and other have the same size and bits.
+func (b *bitset) equals(other *bitset) bool {
+	if b.nbits != other.nbits || len(b.words) != len(other.words) {
+		return false
+	}
+	for i, w := range b.words {
+		if w != other.words[i] {
+			return false
+		}
+	}
+	return true
+}
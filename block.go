@@ -0,0 +1,86 @@
+package bloomfilter
+
+import "math"
+
+// blockBits is the size, in bits, of one block: a single cache line
+// (64 bytes).
+const blockBits = 512
+
+// blockWords is the number of 64-bit words in one block.
+const blockWords = blockBits / 64
+
+// BlockBloomFilter is a drop-in alternative to BloomFilter that trades a
+// slightly higher false positive rate for much better cache locality:
+// every element hashes to exactly one cache-line-sized block, and all k
+// of its bits are set or tested within that single block, so a lookup
+// touches only one cache line instead of k scattered ones.
+type BlockBloomFilter struct {
+	words     []uint64 // numBlocks blocks of blockWords each, packed in order
+	numBlocks int      // Number of blocks
+	k         int      // Number of hash functions per block
+	n         int      // Number of elements in the filter
+	hasher    Hasher   // Produces the two hashes indices are derived from
+}
+
+// NewBlockBloomFilter returns a new BlockBloomFilter, if you pass the
+// number of hash functions to use per block and the number of
+// cache-line-sized blocks to allocate.
+func NewBlockBloomFilter(numHashFuncs, numBlocks int) *BlockBloomFilter {
+	return NewBlockBloomFilterWithHasher(numHashFuncs, numBlocks, fnvHasher{})
+}
+
+// NewBlockBloomFilterWithHasher is like NewBlockBloomFilter, but lets
+// the caller supply a custom Hasher instead of the default FNV-based
+// one.
+func NewBlockBloomFilterWithHasher(numHashFuncs, numBlocks int, h Hasher) *BlockBloomFilter {
+	return &BlockBloomFilter{
+		words:     make([]uint64, numBlocks*blockWords),
+		numBlocks: numBlocks,
+		k:         numHashFuncs,
+		hasher:    h,
+	}
+}
+
+// block picks the single block an element's bits all live in.
+func (bf *BlockBloomFilter) block(h1 uint64) int {
+	return int(h1 % uint64(bf.numBlocks))
+}
+
+// bitInBlock computes the i-th bit position within a block via
+// Kirsch-Mitzenmacher double hashing over [0, blockBits).
+func (bf *BlockBloomFilter) bitInBlock(h1, h2 uint64, i int) uint32 {
+	g := h2 + uint64(i)*(h1^h2) + uint64(i*i)
+	return uint32(g % blockBits)
+}
+
+// Adds an element (in byte-array form) to the Bloom Filter
+func (bf *BlockBloomFilter) Add(e []byte) {
+	h1, h2 := bf.hasher.Hash128(e)
+	base := bf.block(h1) * blockWords
+	for i := 0; i < bf.k; i++ {
+		bit := bf.bitInBlock(h1, h2, i)
+		bf.words[base+int(bit/64)] |= uint64(1) << (bit % 64)
+	}
+	bf.n++
+}
+
+// Checks if an element (in byte-array form) exists in the Bloom Filter
+func (bf *BlockBloomFilter) Check(e []byte) bool {
+	h1, h2 := bf.hasher.Hash128(e)
+	base := bf.block(h1) * blockWords
+	for i := 0; i < bf.k; i++ {
+		bit := bf.bitInBlock(h1, h2, i)
+		if bf.words[base+int(bit/64)]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FalsePositiveRate estimates the current False Positive Rate of the
+// Bloom Filter, treating every block as an independent standard bloom
+// filter of size blockBits loaded with its share of the n elements.
+func (bf *BlockBloomFilter) FalsePositiveRate() float64 {
+	avgPerBlock := float64(bf.n) / float64(bf.numBlocks)
+	return math.Pow((1 - math.Exp(-float64(bf.k)*avgPerBlock/blockBits)), float64(bf.k))
+}
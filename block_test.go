@@ -0,0 +1,45 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestBlockBFBasic(t *testing.T) {
+	bf := NewBlockBloomFilter(4, 64)
+	d1, d2 := []byte("Hello"), []byte("Jello")
+	bf.Add(d1)
+
+	if !bf.Check(d1) {
+		t.Errorf("d1 should be present in the BlockBloomFilter")
+	}
+	if bf.Check(d2) {
+		t.Errorf("d2 should be absent from the BlockBloomFilter")
+	}
+}
+
+func TestBlockBFFalsePositiveRate(t *testing.T) {
+	bf := NewBlockBloomFilter(4, 256)
+
+	for i := 0; i < 1000; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		bf.Add(buf)
+	}
+
+	count := 0
+	for i := 1000; i < 5000; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		if bf.Check(buf) {
+			count++
+		}
+	}
+
+	sensitivity := 0.05
+	expectedFalsePositives := int(4000 * (bf.FalsePositiveRate() + sensitivity))
+	if count > expectedFalsePositives {
+		t.Errorf("Actual false positives %d is greater than max expected false positives %d",
+			count, expectedFalsePositives)
+	}
+}
@@ -25,60 +25,114 @@ SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package bloomfilter
 
 import (
-	"hash"
-	"hash/fnv"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"math"
 )
 
-// The standard bloom filter, which allows adding of 
+// bloomFilterMagic identifies a serialized BloomFilter, so that
+// ReadFrom/UnmarshalBinary can reject data written by something else.
+const bloomFilterMagic uint32 = 0xB10011F5
+
+// bloomFilterVersion is bumped whenever the on-disk format changes.
+const bloomFilterVersion uint8 = 2
+
+// countingBloomFilterMagic identifies a serialized CountingBloomFilter.
+const countingBloomFilterMagic uint32 = 0xC0117186
+
+// countingBloomFilterVersion is bumped whenever the on-disk format changes.
+const countingBloomFilterVersion uint8 = 1
+
+// scalableBloomFilterMagic identifies a serialized ScalableBloomFilter.
+const scalableBloomFilterMagic uint32 = 0x5CA1AB1E
+
+// scalableBloomFilterVersion is bumped whenever the on-disk format changes.
+const scalableBloomFilterVersion uint8 = 1
+
+// estimateParameters computes the bitmap size m and number of hash
+// functions k that minimize the false positive rate for n expected
+// elements at a target rate of fpr, using the standard bloom filter
+// sizing formulas. k is always at least 1.
+func estimateParameters(n uint, fpr float64) (m, k int) {
+	m = int(math.Ceil(-float64(n) * math.Log(fpr) / (math.Ln2 * math.Ln2)))
+	k = int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return m, k
+}
+
+// The standard bloom filter, which allows adding of
 // elements, and checking for their existence
 type BloomFilter struct {
-	bitmap []bool      // The bloom-filter bitmap
-	k      int         // Number of hash functions
-	n      int         // Number of elements in the filter
-	m      int         // Size of the bloom filter
-	hashfn hash.Hash64 // The hash function
+	bitmap *bitset // The bloom-filter bitmap, packed into 64-bit words
+	k      int     // Number of hash functions
+	n      int     // Number of elements in the filter
+	m      int     // Size of the bloom filter
+	hasher Hasher  // Produces the two hashes indices are derived from
 }
 
-// Returns a new BloomFilter object, if you pass the 
+// Returns a new BloomFilter object, if you pass the
 // number of Hash Functions to use and the maximum
 // size of the Bloom Filter
 func NewBloomFilter(numHashFuncs, bfSize int) *BloomFilter {
+	return NewBloomFilterWithHasher(numHashFuncs, bfSize, fnvHasher{})
+}
+
+// NewBloomFilterWithHasher is like NewBloomFilter, but lets the caller
+// supply a custom Hasher (e.g. a keyed hash) instead of the default
+// FNV-based one.
+func NewBloomFilterWithHasher(numHashFuncs, bfSize int, h Hasher) *BloomFilter {
 	bf := new(BloomFilter)
-	bf.bitmap = make([]bool, bfSize)
+	bf.bitmap = newBitset(uint(bfSize))
 	bf.k, bf.m = numHashFuncs, bfSize
 	bf.n = 0
-	bf.hashfn = fnv.New64()
+	bf.hasher = h
 	return bf
 }
 
-func (bf *BloomFilter) getHash(b []byte) (uint32, uint32) {
-	bf.hashfn.Reset()
-	bf.hashfn.Write(b)
-	hash64 := bf.hashfn.Sum64()
-	h1 := uint32(hash64 & ((1 << 32) - 1))
-	h2 := uint32(hash64 >> 32)
-	return h1, h2
+// NewBloomFilterWithEstimates returns a new BloomFilter sized to hold
+// n elements at a target false positive rate of fpr, computing the
+// optimal bitmap size m and number of hash functions k for you:
+//
+//	m = -n * ln(fpr) / (ln 2)^2
+//	k = (m / n) * ln 2
+//
+// This is friendlier than NewBloomFilter for callers who don't want to
+// work out k and m by hand.
+func NewBloomFilterWithEstimates(n uint, fpr float64) *BloomFilter {
+	m, k := estimateParameters(n, fpr)
+	return NewBloomFilter(k, m)
+}
+
+// index computes the bit position for the i-th hash function using
+// Kirsch-Mitzenmacher double hashing, g_i = h1 + i*h2 + i^2. The
+// quadratic term avoids the pathological index collapse that plain
+// g_i = h1 + i*h2 suffers when h2 happens to be small.
+func (bf *BloomFilter) index(h1, h2 uint64, i int) uint32 {
+	g := h1 + uint64(i)*h2 + uint64(i*i)
+	return uint32(g % uint64(bf.m))
 }
 
 // Adds an element (in byte-array form) to the Bloom Filter
 func (bf *BloomFilter) Add(e []byte) {
-	h1, h2 := bf.getHash(e)
+	h1, h2 := bf.hasher.Hash128(e)
 	for i := 0; i < bf.k; i++ {
-		ind := (h1 + uint32(i)*h2) % uint32(bf.m)
-		bf.bitmap[ind] = true
+		bf.bitmap.set(uint(bf.index(h1, h2, i)))
 	}
 	bf.n++
 }
 
-// Checks if an element (in byte-array form) exists in the 
+// Checks if an element (in byte-array form) exists in the
 // Bloom Filter
 func (bf *BloomFilter) Check(x []byte) bool {
-	h1, h2 := bf.getHash(x)
+	h1, h2 := bf.hasher.Hash128(x)
 	result := true
 	for i := 0; i < bf.k; i++ {
-		ind := (h1 + uint32(i)*h2) % uint32(bf.m)
-		result = result && bf.bitmap[ind]
+		result = result && bf.bitmap.get(uint(bf.index(h1, h2, i)))
 	}
 	return result
 }
@@ -89,41 +143,182 @@ func (bf *BloomFilter) FalsePositiveRate() float64 {
 		float64(bf.m))), float64(bf.k))
 }
 
-// A Bloom Filter which allows deletion of elements. 
+// Count returns the number of bits currently set in the filter's bitmap
+// (its population count), which is always <= m.
+func (bf *BloomFilter) Count() int {
+	return int(bf.bitmap.count())
+}
+
+// Union sets bf's bitmap to the bitwise union of bf and other, so that bf
+// will report Check(x) == true for anything either filter would have.
+// bf and other must have identical k and m.
+func (bf *BloomFilter) Union(other *BloomFilter) error {
+	if bf.k != other.k || bf.m != other.m {
+		return errors.New("bloomfilter: Union requires filters with identical k and m")
+	}
+	bf.bitmap.union(other.bitmap)
+	return nil
+}
+
+// Intersect sets bf's bitmap to the bitwise intersection of bf and other,
+// so that bf reports Check(x) == true only where both filters would. The
+// result may have a higher false positive rate than a filter built
+// directly from the elements the two have in common, since a bit can
+// survive the intersection because different elements set it in each.
+// bf and other must have identical k and m.
+func (bf *BloomFilter) Intersect(other *BloomFilter) error {
+	if bf.k != other.k || bf.m != other.m {
+		return errors.New("bloomfilter: Intersect requires filters with identical k and m")
+	}
+	bf.bitmap.intersect(other.bitmap)
+	return nil
+}
+
+// Equals reports whether bf and other have identical k, m, and bitmap
+// contents.
+func (bf *BloomFilter) Equals(other *BloomFilter) bool {
+	return bf.k == other.k && bf.m == other.m && bf.bitmap.equals(other.bitmap)
+}
+
+// MarshalBinary encodes bf into a portable binary form suitable for
+// writing to disk or sending over the network. See WriteTo for the
+// format.
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if _, err := bf.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a BloomFilter previously encoded with
+// MarshalBinary or WriteTo, replacing bf's contents.
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	_, err := bf.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// WriteTo writes bf to w in a portable binary format: a magic number,
+// version byte, and hasher ID, followed by k, m, and n (all
+// little-endian uint32), and finally the packed bitmap words
+// (little-endian uint64). It satisfies io.WriterTo.
+func (bf *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 4+1+1+4+4+4)
+	binary.LittleEndian.PutUint32(header[0:4], bloomFilterMagic)
+	header[4] = bloomFilterVersion
+	header[5] = bf.hasher.HasherID()
+	binary.LittleEndian.PutUint32(header[6:10], uint32(bf.k))
+	binary.LittleEndian.PutUint32(header[10:14], uint32(bf.m))
+	binary.LittleEndian.PutUint32(header[14:18], uint32(bf.n))
+
+	written, err := w.Write(header)
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	wordBuf := make([]byte, 8)
+	for _, word := range bf.bitmap.words {
+		binary.LittleEndian.PutUint64(wordBuf, word)
+		n, err := w.Write(wordBuf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom reads a BloomFilter previously written by WriteTo from r,
+// replacing bf's k, m, n, hasher, and bitmap. It satisfies
+// io.ReaderFrom.
+func (bf *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, 4+1+1+4+4+4)
+	read, err := io.ReadFull(r, header)
+	total := int64(read)
+	if err != nil {
+		return total, err
+	}
+
+	magic := binary.LittleEndian.Uint32(header[0:4])
+	if magic != bloomFilterMagic {
+		return total, errors.New("bloomfilter: data is not a serialized BloomFilter")
+	}
+	if version := header[4]; version != bloomFilterVersion {
+		return total, fmt.Errorf("bloomfilter: unsupported serialization version %d", version)
+	}
+
+	newHasher, ok := hasherRegistry[header[5]]
+	if !ok {
+		return total, fmt.Errorf("bloomfilter: unregistered hasher id %d", header[5])
+	}
+
+	bf.k = int(binary.LittleEndian.Uint32(header[6:10]))
+	bf.m = int(binary.LittleEndian.Uint32(header[10:14]))
+	bf.n = int(binary.LittleEndian.Uint32(header[14:18]))
+	bf.bitmap = newBitset(uint(bf.m))
+	bf.hasher = newHasher()
+
+	wordBuf := make([]byte, 8)
+	for i := range bf.bitmap.words {
+		n, err := io.ReadFull(r, wordBuf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		bf.bitmap.words[i] = binary.LittleEndian.Uint64(wordBuf)
+	}
+	return total, nil
+}
+
+// A Bloom Filter which allows deletion of elements.
 // An 8-bit counter is maintained for each slot. This should
 // be accounted for while deciding the size of the new filter.
 type CountingBloomFilter struct {
-	counts []uint8     // The bloom-filter bitmap
-	k      int         // Number of hash functions
-	n      int         // Number of elements in the filter
-	m      int         // Size of the bloom filter
-	hashfn hash.Hash64 // The hash function
+	counts []uint8 // The bloom-filter bitmap
+	k      int     // Number of hash functions
+	n      int     // Number of elements in the filter
+	m      int     // Size of the bloom filter
+	hasher Hasher  // Produces the two hashes indices are derived from
 }
 
 // Creates a new Counting Bloom Filter
 func NewCountingBloomFilter(numHashFuncs, cbfSize int) *CountingBloomFilter {
+	return NewCountingBloomFilterWithHasher(numHashFuncs, cbfSize, fnvHasher{})
+}
+
+// NewCountingBloomFilterWithHasher is like NewCountingBloomFilter, but
+// lets the caller supply a custom Hasher instead of the default
+// FNV-based one.
+func NewCountingBloomFilterWithHasher(numHashFuncs, cbfSize int, h Hasher) *CountingBloomFilter {
 	cbf := new(CountingBloomFilter)
 	cbf.counts = make([]uint8, cbfSize)
 	cbf.k, cbf.m = numHashFuncs, cbfSize
 	cbf.n = 0
-	cbf.hashfn = fnv.New64()
+	cbf.hasher = h
 	return cbf
 }
 
-func (cbf *CountingBloomFilter) getHash(b []byte) (uint32, uint32) {
-	cbf.hashfn.Reset()
-	cbf.hashfn.Write(b)
-	hash64 := cbf.hashfn.Sum64()
-	h1 := uint32(hash64 & ((1 << 32) - 1))
-	h2 := uint32(hash64 >> 32)
-	return h1, h2
+// NewCountingBloomFilterWithEstimates returns a new CountingBloomFilter
+// sized to hold n elements at a target false positive rate of fpr. See
+// NewBloomFilterWithEstimates for the sizing formula.
+func NewCountingBloomFilterWithEstimates(n uint, fpr float64) *CountingBloomFilter {
+	m, k := estimateParameters(n, fpr)
+	return NewCountingBloomFilter(k, m)
+}
+
+// index computes the bit position for the i-th hash function; see
+// BloomFilter.index for the double hashing scheme.
+func (cbf *CountingBloomFilter) index(h1, h2 uint64, i int) uint32 {
+	g := h1 + uint64(i)*h2 + uint64(i*i)
+	return uint32(g % uint64(cbf.m))
 }
 
 // Adds an element (in byte-array form) to the Counting Bloom Filter
 func (cbf *CountingBloomFilter) Add(e []byte) {
-	h1, h2 := cbf.getHash(e)
+	h1, h2 := cbf.hasher.Hash128(e)
 	for i := 0; i < cbf.k; i++ {
-		ind := (h1 + uint32(i)*h2) % uint32(cbf.m)
+		ind := cbf.index(h1, h2, i)
 		// Guarding against an overflow
 		if cbf.counts[ind] < 0xFF {
 			cbf.counts[ind] += 1
@@ -134,9 +329,9 @@ func (cbf *CountingBloomFilter) Add(e []byte) {
 
 // Removes an element (in byte-array form) from the Counting Bloom Filter
 func (cbf *CountingBloomFilter) Remove(e []byte) {
-	h1, h2 := cbf.getHash(e)
+	h1, h2 := cbf.hasher.Hash128(e)
 	for i := 0; i < cbf.k; i++ {
-		ind := (h1 + uint32(i)*h2) % uint32(cbf.m)
+		ind := cbf.index(h1, h2, i)
 
 		if cbf.counts[ind] > 0 {
 			// Guarding against an underflow
@@ -146,88 +341,361 @@ func (cbf *CountingBloomFilter) Remove(e []byte) {
 	cbf.n--
 }
 
-// Checks if an element (in byte-array form) exists in the 
+// Checks if an element (in byte-array form) exists in the
 // Counting Bloom Filter
 func (cbf *CountingBloomFilter) Check(x []byte) bool {
-	h1, h2 := cbf.getHash(x)
+	h1, h2 := cbf.hasher.Hash128(x)
 	result := true
 	for i := 0; i < cbf.k; i++ {
-		ind := (h1 + uint32(i)*h2) % uint32(cbf.m)
-		result = result && (cbf.counts[ind] > 0)
+		result = result && (cbf.counts[cbf.index(h1, h2, i)] > 0)
 	}
 	return result
 }
 
-// A scalable bloom filter, which allows adding of 
+// Count returns the number of slots currently holding a non-zero
+// counter, which is always <= m.
+func (cbf *CountingBloomFilter) Count() int {
+	c := 0
+	for _, v := range cbf.counts {
+		if v > 0 {
+			c++
+		}
+	}
+	return c
+}
+
+// Equals reports whether cbf and other have identical k, m, and counts.
+func (cbf *CountingBloomFilter) Equals(other *CountingBloomFilter) bool {
+	if cbf.k != other.k || cbf.m != other.m {
+		return false
+	}
+	for i := range cbf.counts {
+		if cbf.counts[i] != other.counts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBinary encodes cbf into a portable binary form suitable for
+// writing to disk or sending over the network. See WriteTo for the
+// format.
+func (cbf *CountingBloomFilter) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if _, err := cbf.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a CountingBloomFilter previously encoded with
+// MarshalBinary or WriteTo, replacing cbf's contents.
+func (cbf *CountingBloomFilter) UnmarshalBinary(data []byte) error {
+	_, err := cbf.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// WriteTo writes cbf to w in a portable binary format: a magic number,
+// version byte, and hasher ID, followed by k, m, and n (all
+// little-endian uint32), and finally the raw counts bytes (one byte per
+// slot, since counts are already []uint8). It satisfies io.WriterTo.
+func (cbf *CountingBloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 4+1+1+4+4+4)
+	binary.LittleEndian.PutUint32(header[0:4], countingBloomFilterMagic)
+	header[4] = countingBloomFilterVersion
+	header[5] = cbf.hasher.HasherID()
+	binary.LittleEndian.PutUint32(header[6:10], uint32(cbf.k))
+	binary.LittleEndian.PutUint32(header[10:14], uint32(cbf.m))
+	binary.LittleEndian.PutUint32(header[14:18], uint32(cbf.n))
+
+	written, err := w.Write(header)
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	n, err := w.Write(cbf.counts)
+	total += int64(n)
+	return total, err
+}
+
+// ReadFrom reads a CountingBloomFilter previously written by WriteTo
+// from r, replacing cbf's contents. It satisfies io.ReaderFrom.
+func (cbf *CountingBloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, 4+1+1+4+4+4)
+	read, err := io.ReadFull(r, header)
+	total := int64(read)
+	if err != nil {
+		return total, err
+	}
+
+	magic := binary.LittleEndian.Uint32(header[0:4])
+	if magic != countingBloomFilterMagic {
+		return total, errors.New("bloomfilter: data is not a serialized CountingBloomFilter")
+	}
+	if version := header[4]; version != countingBloomFilterVersion {
+		return total, fmt.Errorf("bloomfilter: unsupported serialization version %d", version)
+	}
+
+	newHasher, ok := hasherRegistry[header[5]]
+	if !ok {
+		return total, fmt.Errorf("bloomfilter: unregistered hasher id %d", header[5])
+	}
+
+	cbf.k = int(binary.LittleEndian.Uint32(header[6:10]))
+	cbf.m = int(binary.LittleEndian.Uint32(header[10:14]))
+	cbf.n = int(binary.LittleEndian.Uint32(header[14:18]))
+	cbf.hasher = newHasher()
+	cbf.counts = make([]uint8, cbf.m)
+
+	n, err := io.ReadFull(r, cbf.counts)
+	total += int64(n)
+	return total, err
+}
+
+// scalableSlice is one constituent filter of a ScalableBloomFilter,
+// together with the capacity it was sized for and how full it is.
+type scalableSlice struct {
+	bf       *BloomFilter
+	capacity int
+	fill     int
+}
+
+// A scalable bloom filter, which allows adding of
 // elements, and checking for their existence
+//
+// It follows Almeida, Baquero, Preguica, and Hutchison's "Scalable
+// Bloom Filters": rather than giving every slice the same size and
+// target false positive rate, slice i has size m0*growth^i and its own
+// target rate p0*tightening^i, so the geometric sum of per-slice rates
+// stays bounded by p0/(1-tightening) no matter how many slices are
+// added.
 type ScalableBloomFilter struct {
-	bfArr []BloomFilter // The list of Bloom Filters
-	k     int           // Number of hash functions
-	n     int           // Number of elements in the filter
-	m     int           // Size of the smallest bloom filter
-	p     int           // Maximum number of bloom filters to support. 	
-	q     int           // Number of bloom filters present in the list.
-	r     int           // Multiplication factor for new bloom filter sizes
-	s     int           // Size of the current bloom filter
-	f     float64       // Target False Positive rate / bf
-}
-
-// Returns a new Scalable BloomFilter object, if you pass in
-// valid values for all the required fields.
-// firstBFSize is the size of the first Bloom Filter which
-// will be created.
-// maxBloomFilters is the upper limit on the number of 
-// Bloom Filters to create
-// growthFactor is the rate at which the Bloom Filter size grows.
-// targetFPR is the maximum false positive rate allowed for each
-// of the constituent bloom filters, after which a new Bloom
-// Filter would be created and used
-func NewScalableBloomFilter(numHashFuncs, firstBFSize, maxBloomFilters, growthFactor int, targetFPR float64) *ScalableBloomFilter {
-	sbf := new(ScalableBloomFilter)
-	sbf.k, sbf.n, sbf.m, sbf.p, sbf.q, sbf.r, sbf.f = numHashFuncs, 0, firstBFSize, maxBloomFilters, 1, growthFactor, targetFPR
-	sbf.s = sbf.m
-	sbf.bfArr = make([]BloomFilter, 0, maxBloomFilters)
-	bf := NewBloomFilter(sbf.k, sbf.m)
-	sbf.bfArr = append(sbf.bfArr, *bf)
+	slices     []*scalableSlice
+	m0         uint    // Size of the first slice
+	p0         float64 // Target False Positive rate of the first slice
+	growth     float64 // Size growth factor applied to each new slice
+	tightening float64 // Target FPR tightening ratio applied to each new slice
+	n          int     // Number of elements in the filter
+	hasher     Hasher  // Hasher used to build every slice's BloomFilter
+}
+
+// Returns a new ScalableBloomFilter, sized so its first slice holds
+// initialCapacity elements at a false positive rate of fpr. growth is
+// the size multiplier applied to each new slice (2-4 is typical);
+// tightening is the false positive rate multiplier applied to each new
+// slice's target rate, and must be in (0, 1) (0.8-0.9 is typical) for
+// the cumulative false positive rate to stay bounded.
+func NewScalableBloomFilter(initialCapacity uint, fpr float64, growth float64, tightening float64) *ScalableBloomFilter {
+	return NewScalableBloomFilterWithHasher(initialCapacity, fpr, growth, tightening, fnvHasher{})
+}
+
+// NewScalableBloomFilterWithHasher is like NewScalableBloomFilter, but
+// lets the caller supply a custom Hasher instead of the default
+// FNV-based one. Every slice is built with this hasher.
+func NewScalableBloomFilterWithHasher(initialCapacity uint, fpr float64, growth float64, tightening float64, h Hasher) *ScalableBloomFilter {
+	m0, _ := estimateParameters(initialCapacity, fpr)
+	sbf := &ScalableBloomFilter{
+		m0:         uint(m0),
+		p0:         fpr,
+		growth:     growth,
+		tightening: tightening,
+		hasher:     h,
+	}
+	sbf.slices = append(sbf.slices, sbf.newSlice(0))
 	return sbf
 }
 
+// NewScalableBloomFilterWithEstimates returns a new ScalableBloomFilter
+// whose first slice is sized to hold initialCapacity elements at a
+// target false positive rate of fpr, using a growth factor of 2 and a
+// tightening ratio of 0.9. Use NewScalableBloomFilter directly to tune
+// those.
+func NewScalableBloomFilterWithEstimates(initialCapacity uint, fpr float64) *ScalableBloomFilter {
+	return NewScalableBloomFilter(initialCapacity, fpr, 2, 0.9)
+}
+
+// newSlice builds the i-th slice: size m0*growth^i, target false
+// positive rate p0*tightening^i, and k sized to hit that rate.
+func (sbf *ScalableBloomFilter) newSlice(i int) *scalableSlice {
+	m := int(float64(sbf.m0) * math.Pow(sbf.growth, float64(i)))
+	p := sbf.p0 * math.Pow(sbf.tightening, float64(i))
+	k := int(math.Ceil(math.Log2(1 / p)))
+	if k < 1 {
+		k = 1
+	}
+	return &scalableSlice{
+		bf:       NewBloomFilterWithHasher(k, m, sbf.hasher),
+		capacity: int(float64(m) * math.Ln2 * math.Ln2 / math.Abs(math.Log(p))),
+	}
+}
+
 // Adds an element of type byte-array to the Bloom Filter
 func (sbf *ScalableBloomFilter) Add(e []byte) {
-	inuseFilter := sbf.q - 1
-	fpr := sbf.bfArr[inuseFilter].FalsePositiveRate()
-	if fpr <= sbf.f {
-		sbf.bfArr[inuseFilter].Add(e)
-		sbf.n++
-	} else {
-		if sbf.p == sbf.q {
-			return
-		}
-		sbf.s = sbf.s * sbf.r
-		bf := NewBloomFilter(sbf.k, sbf.s)
-		sbf.bfArr = append(sbf.bfArr, *bf)
-		sbf.q++
-		inuseFilter = sbf.q - 1
-		sbf.bfArr[inuseFilter].Add(e)
-		sbf.n++
+	cur := sbf.slices[len(sbf.slices)-1]
+	if cur.fill >= cur.capacity {
+		cur = sbf.newSlice(len(sbf.slices))
+		sbf.slices = append(sbf.slices, cur)
 	}
+	cur.bf.Add(e)
+	cur.fill++
+	sbf.n++
 }
 
 // Returns the cumulative False Positive Rate of the filter
 func (sbf *ScalableBloomFilter) FalsePositiveRate() float64 {
 	res := 1.0
-	for i := 0; i < sbf.q; i++ {
-		res *= (1.0 - sbf.bfArr[i].FalsePositiveRate())
+	for _, s := range sbf.slices {
+		res *= 1.0 - s.bf.FalsePositiveRate()
 	}
 	return 1.0 - res
 }
 
 // Checks if an element (in byte-array form) exists
 func (sbf *ScalableBloomFilter) Check(e []byte) bool {
-	for i := 0; i < sbf.q; i++ {
-		if sbf.bfArr[i].Check(e) {
+	for _, s := range sbf.slices {
+		if s.bf.Check(e) {
 			return true
 		}
 	}
 	return false
 }
+
+// Count returns the number of bits currently set across every slice's
+// bitmap.
+func (sbf *ScalableBloomFilter) Count() int {
+	c := 0
+	for _, s := range sbf.slices {
+		c += s.bf.Count()
+	}
+	return c
+}
+
+// Equals reports whether sbf and other have identical sizing parameters
+// and slices (each slice compared with BloomFilter.Equals, plus fill).
+func (sbf *ScalableBloomFilter) Equals(other *ScalableBloomFilter) bool {
+	if sbf.m0 != other.m0 || sbf.p0 != other.p0 ||
+		sbf.growth != other.growth || sbf.tightening != other.tightening {
+		return false
+	}
+	if len(sbf.slices) != len(other.slices) {
+		return false
+	}
+	for i, s := range sbf.slices {
+		o := other.slices[i]
+		if s.fill != o.fill || s.capacity != o.capacity || !s.bf.Equals(o.bf) {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBinary encodes sbf into a portable binary form suitable for
+// writing to disk or sending over the network. See WriteTo for the
+// format.
+func (sbf *ScalableBloomFilter) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if _, err := sbf.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a ScalableBloomFilter previously encoded with
+// MarshalBinary or WriteTo, replacing sbf's contents.
+func (sbf *ScalableBloomFilter) UnmarshalBinary(data []byte) error {
+	_, err := sbf.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// WriteTo writes sbf to w in a portable binary format: a magic number
+// and version byte, followed by m0, n, and the number of slices (all
+// little-endian uint32), p0, growth, and tightening (all little-endian
+// float64), and finally each slice in order, as its fill (little-endian
+// uint32) followed by its BloomFilter.WriteTo encoding (which carries
+// its own hasher ID). It satisfies io.WriterTo.
+func (sbf *ScalableBloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 4+1+4+4+4+8+8+8)
+	binary.LittleEndian.PutUint32(header[0:4], scalableBloomFilterMagic)
+	header[4] = scalableBloomFilterVersion
+	binary.LittleEndian.PutUint32(header[5:9], uint32(sbf.m0))
+	binary.LittleEndian.PutUint32(header[9:13], uint32(sbf.n))
+	binary.LittleEndian.PutUint32(header[13:17], uint32(len(sbf.slices)))
+	binary.LittleEndian.PutUint64(header[17:25], math.Float64bits(sbf.p0))
+	binary.LittleEndian.PutUint64(header[25:33], math.Float64bits(sbf.growth))
+	binary.LittleEndian.PutUint64(header[33:41], math.Float64bits(sbf.tightening))
+
+	written, err := w.Write(header)
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	fillBuf := make([]byte, 4)
+	for _, s := range sbf.slices {
+		binary.LittleEndian.PutUint32(fillBuf, uint32(s.fill))
+		n, err := w.Write(fillBuf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+
+		sliceWritten, err := s.bf.WriteTo(w)
+		total += sliceWritten
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom reads a ScalableBloomFilter previously written by WriteTo
+// from r, replacing sbf's contents. It satisfies io.ReaderFrom.
+func (sbf *ScalableBloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, 4+1+4+4+4+8+8+8)
+	read, err := io.ReadFull(r, header)
+	total := int64(read)
+	if err != nil {
+		return total, err
+	}
+
+	magic := binary.LittleEndian.Uint32(header[0:4])
+	if magic != scalableBloomFilterMagic {
+		return total, errors.New("bloomfilter: data is not a serialized ScalableBloomFilter")
+	}
+	if version := header[4]; version != scalableBloomFilterVersion {
+		return total, fmt.Errorf("bloomfilter: unsupported serialization version %d", version)
+	}
+
+	sbf.m0 = uint(binary.LittleEndian.Uint32(header[5:9]))
+	sbf.n = int(binary.LittleEndian.Uint32(header[9:13]))
+	numSlices := int(binary.LittleEndian.Uint32(header[13:17]))
+	sbf.p0 = math.Float64frombits(binary.LittleEndian.Uint64(header[17:25]))
+	sbf.growth = math.Float64frombits(binary.LittleEndian.Uint64(header[25:33]))
+	sbf.tightening = math.Float64frombits(binary.LittleEndian.Uint64(header[33:41]))
+
+	fillBuf := make([]byte, 4)
+	sbf.slices = make([]*scalableSlice, numSlices)
+	for i := range sbf.slices {
+		n, err := io.ReadFull(r, fillBuf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+
+		bf := new(BloomFilter)
+		sliceRead, err := bf.ReadFrom(r)
+		total += sliceRead
+		if err != nil {
+			return total, err
+		}
+
+		sbf.slices[i] = &scalableSlice{
+			bf:       bf,
+			fill:     int(binary.LittleEndian.Uint32(fillBuf)),
+			capacity: int(float64(bf.m) * math.Ln2 * math.Ln2 / math.Abs(math.Log(sbf.p0*math.Pow(sbf.tightening, float64(i))))),
+		}
+	}
+	sbf.hasher = sbf.slices[0].bf.hasher
+	return total, nil
+}
@@ -6,43 +6,71 @@ import (
 )
 
 func TestBasic(t *testing.T) {
-	bf := newBloomFilter(3, 100)
+	bf := NewBloomFilter(3, 100)
 	d1, d2 := []byte("Hello"), []byte("Jello")
-	bf.add(d1)
+	bf.Add(d1)
 
-	if !bf.check(d1) {
+	if !bf.Check(d1) {
 		t.Errorf("d1 should be present in the BloomFilter")
 	}
 
-	if bf.check(d2) {
+	if bf.Check(d2) {
 		t.Errorf("d2 should be absent from the BloomFilter")
 	}
 }
 
 func TestCountingBFBasic(t *testing.T) {
-	cbf := newCountingBloomFilter(3, 100)
+	cbf := NewCountingBloomFilter(3, 100)
 	d1 := []byte("Hello")
-	cbf.add(d1)
+	cbf.Add(d1)
 
-	if !cbf.check(d1) {
+	if !cbf.Check(d1) {
 		t.Errorf("d1 should be present in the BloomFilter")
 	}
 
-	cbf.remove(d1)
+	cbf.Remove(d1)
 
-	if cbf.check(d1) {
+	if cbf.Check(d1) {
 		t.Errorf("d1 should be absent from the BloomFilter after deletion")
 	}
 }
 
+func TestCountingBFCountEqualsAndSerialize(t *testing.T) {
+	cbf := NewCountingBloomFilter(3, 100)
+	if cbf.Count() != 0 {
+		t.Errorf("a fresh filter should have Count() == 0")
+	}
+	cbf.Add([]byte("Hello"))
+	if cbf.Count() == 0 || cbf.Count() > cbf.k {
+		t.Errorf("Count() should be between 1 and k after one Add, got %d", cbf.Count())
+	}
+
+	data, err := cbf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	restored := new(CountingBloomFilter)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+
+	if !cbf.Equals(restored) {
+		t.Errorf("restored filter should equal the original")
+	}
+	if !restored.Check([]byte("Hello")) {
+		t.Errorf("restored filter should still contain the original element")
+	}
+}
+
 func TestScalableBFBasic(t *testing.T) {
-	sbf := newScalableBloomFilter(3, 20, 4, 10, 0.01)
+	sbf := NewScalableBloomFilter(20, 0.01, 2, 0.9)
 
 	for i := 1; i < 1000; i++ {
 		buf := make([]byte, 8)
 		binary.PutVarint(buf, int64(i))
-		sbf.add(buf)
-		if !sbf.check(buf) {
+		sbf.Add(buf)
+		if !sbf.Check(buf) {
 			t.Errorf("%d should be present in the BloomFilter", i)
 			return
 		}
@@ -51,7 +79,7 @@ func TestScalableBFBasic(t *testing.T) {
 	for i := 1; i < 1000; i++ {
 		buf := make([]byte, 8)
 		binary.PutVarint(buf, int64(i))
-		if !sbf.check(buf) {
+		if !sbf.Check(buf) {
 			t.Errorf("%d should be present in the BloomFilter", i)
 			return
 		}
@@ -62,19 +90,21 @@ func TestScalableBFBasic(t *testing.T) {
 	for i := 1000; i < 4000; i++ {
 		buf := make([]byte, 8)
 		binary.PutVarint(buf, int64(i))
-		if sbf.check(buf) {
+		if sbf.Check(buf) {
 			count++
 		}
 	}
 
-	if sbf.falsePositiveRate() > 0.04 {
-		t.Errorf("False Positive Rate for this test should be < 0.04")
+	// The tightening ratio bounds the cumulative false positive rate at
+	// p0/(1-tightening), which for these parameters is 0.01/(1-0.9) = 0.1.
+	if sbf.FalsePositiveRate() > 0.1 {
+		t.Errorf("False Positive Rate for this test should be < 0.1")
 		return
 	}
 
-	sensitivity := 0.01 // TODO Make this configurable
+	sensitivity := 0.03 // TODO Make this configurable
 	expectedFalsePositives :=
-		(int)((4000 - 1000) * (sbf.falsePositiveRate() + sensitivity))
+		(int)((4000 - 1000) * (sbf.FalsePositiveRate() + sensitivity))
 	if count > expectedFalsePositives {
 		t.Errorf("Actual false positives %d is greater than max expected false positives %d",
 			count,
@@ -82,3 +112,184 @@ func TestScalableBFBasic(t *testing.T) {
 		return
 	}
 }
+
+func TestScalableBFSerializeRoundTrip(t *testing.T) {
+	sbf := NewScalableBloomFilter(20, 0.01, 2, 0.9)
+	for i := 1; i < 1000; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		sbf.Add(buf)
+	}
+
+	data, err := sbf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	restored := new(ScalableBloomFilter)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+
+	if !sbf.Equals(restored) {
+		t.Errorf("restored filter should equal the original")
+	}
+	for i := 1; i < 1000; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		if !restored.Check(buf) {
+			t.Errorf("%d should still be present after round-tripping", i)
+		}
+	}
+}
+
+func TestNewWithEstimates(t *testing.T) {
+	bf := NewBloomFilterWithEstimates(1000, 0.01)
+	if bf.k < 1 || bf.m <= 0 {
+		t.Errorf("expected positive k and m, got k=%d m=%d", bf.k, bf.m)
+	}
+
+	for i := 0; i < 1000; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		bf.Add(buf)
+	}
+	if bf.FalsePositiveRate() > 0.02 {
+		t.Errorf("expected false positive rate close to 0.01, got %f", bf.FalsePositiveRate())
+	}
+
+	cbf := NewCountingBloomFilterWithEstimates(1000, 0.01)
+	if cbf.k < 1 || cbf.m <= 0 {
+		t.Errorf("expected positive k and m, got k=%d m=%d", cbf.k, cbf.m)
+	}
+
+	sbf := NewScalableBloomFilterWithEstimates(20, 0.01)
+	if len(sbf.slices) != 1 || sbf.slices[0].capacity <= 0 {
+		t.Errorf("expected a single slice with positive capacity, got %+v", sbf.slices)
+	}
+}
+
+func TestUnionAndIntersect(t *testing.T) {
+	bf1 := NewBloomFilter(3, 100)
+	bf2 := NewBloomFilter(3, 100)
+	d1, d2 := []byte("Hello"), []byte("Jello")
+	bf1.Add(d1)
+	bf2.Add(d2)
+
+	if err := bf1.Union(bf2); err != nil {
+		t.Fatalf("Union failed: %v", err)
+	}
+	if !bf1.Check(d1) || !bf1.Check(d2) {
+		t.Errorf("union should contain elements from both filters")
+	}
+
+	other := NewBloomFilter(3, 50)
+	if err := bf1.Union(other); err == nil {
+		t.Errorf("Union of filters with different m should fail")
+	}
+
+	bf3 := NewBloomFilter(3, 100)
+	bf3.Add(d1)
+	bf4 := NewBloomFilter(3, 100)
+	bf4.Add(d1)
+	bf4.Add(d2)
+	if err := bf4.Intersect(bf3); err != nil {
+		t.Fatalf("Intersect failed: %v", err)
+	}
+	if !bf4.Equals(bf3) {
+		t.Errorf("intersection of bf4 with bf3 should equal bf3")
+	}
+}
+
+func TestCount(t *testing.T) {
+	bf := NewBloomFilter(3, 1000)
+	if bf.Count() != 0 {
+		t.Errorf("a fresh filter should have Count() == 0")
+	}
+	bf.Add([]byte("Hello"))
+	if bf.Count() == 0 || bf.Count() > bf.k {
+		t.Errorf("Count() should be between 1 and k after one Add, got %d", bf.Count())
+	}
+}
+
+type reversingHasher struct{}
+
+func (reversingHasher) Hash128(b []byte) (uint64, uint64) {
+	rev := make([]byte, len(b))
+	for i, c := range b {
+		rev[len(b)-1-i] = c
+	}
+	h1 := fnvHasher{}
+	a1, a2 := h1.Hash128(b)
+	b1, _ := h1.Hash128(rev)
+	return a1, a2 ^ b1
+}
+
+func (reversingHasher) HasherID() uint8 { return 200 }
+
+func TestCustomHasher(t *testing.T) {
+	RegisterHasher(200, func() Hasher { return reversingHasher{} })
+
+	bf := NewBloomFilterWithHasher(4, 1000, reversingHasher{})
+	bf.Add([]byte("Hello"))
+	if !bf.Check([]byte("Hello")) {
+		t.Errorf("d1 should be present in the BloomFilter")
+	}
+	if bf.Check([]byte("Jello")) {
+		t.Errorf("d2 should be absent from the BloomFilter")
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	restored := new(BloomFilter)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if !restored.Check([]byte("Hello")) {
+		t.Errorf("restored filter should still contain the original element")
+	}
+}
+
+func TestScalableBFCustomHasher(t *testing.T) {
+	RegisterHasher(200, func() Hasher { return reversingHasher{} })
+
+	sbf := NewScalableBloomFilterWithHasher(20, 0.01, 2, 0.9, reversingHasher{})
+	for _, s := range sbf.slices {
+		if _, ok := s.bf.hasher.(reversingHasher); !ok {
+			t.Errorf("expected slice to use reversingHasher, got %T", s.bf.hasher)
+		}
+	}
+
+	sbf.Add([]byte("Hello"))
+	if !sbf.Check([]byte("Hello")) {
+		t.Errorf("d1 should be present in the ScalableBloomFilter")
+	}
+	if sbf.Check([]byte("Jello")) {
+		t.Errorf("d2 should be absent from the ScalableBloomFilter")
+	}
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	bf := NewBloomFilter(4, 1000)
+	bf.Add([]byte("Hello"))
+	bf.Add([]byte("World"))
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	restored := new(BloomFilter)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+
+	if !bf.Equals(restored) {
+		t.Errorf("restored filter should equal the original")
+	}
+	if !restored.Check([]byte("Hello")) || !restored.Check([]byte("World")) {
+		t.Errorf("restored filter should still contain the original elements")
+	}
+}
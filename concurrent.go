@@ -0,0 +1,123 @@
+package bloomfilter
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// ConcurrentBloomFilter is a BloomFilter variant whose Add and Check are
+// safe to call from many goroutines at once without a lock. Bits are
+// set with a compare-and-swap retry loop rather than a mutex, so
+// concurrent inserts never block each other.
+type ConcurrentBloomFilter struct {
+	words  []uint64 // The bloom-filter bitmap, packed into 64-bit words
+	k      int      // Number of hash functions
+	m      int      // Size of the bloom filter
+	n      int64    // Number of elements in the filter, updated atomically
+	hasher Hasher   // Produces the two hashes indices are derived from
+}
+
+// NewConcurrentBloomFilter returns a new ConcurrentBloomFilter, if you
+// pass the number of Hash Functions to use and the maximum size of the
+// Bloom Filter.
+func NewConcurrentBloomFilter(numHashFuncs, bfSize int) *ConcurrentBloomFilter {
+	return NewConcurrentBloomFilterWithHasher(numHashFuncs, bfSize, fnvHasher{})
+}
+
+// NewConcurrentBloomFilterWithHasher is like NewConcurrentBloomFilter,
+// but lets the caller supply a custom Hasher instead of the default
+// FNV-based one.
+func NewConcurrentBloomFilterWithHasher(numHashFuncs, bfSize int, h Hasher) *ConcurrentBloomFilter {
+	return &ConcurrentBloomFilter{
+		words:  make([]uint64, (uint(bfSize)+63)/64),
+		k:      numHashFuncs,
+		m:      bfSize,
+		hasher: h,
+	}
+}
+
+// index computes the bit position for the i-th hash function; see
+// BloomFilter.index for the double hashing scheme.
+func (cbf *ConcurrentBloomFilter) index(h1, h2 uint64, i int) uint32 {
+	g := h1 + uint64(i)*h2 + uint64(i*i)
+	return uint32(g % uint64(cbf.m))
+}
+
+// setBit atomically sets bit i, retrying the compare-and-swap until it
+// succeeds or another goroutine has already set it.
+func (cbf *ConcurrentBloomFilter) setBit(i uint32) {
+	addr := &cbf.words[i/64]
+	mask := uint64(1) << (i % 64)
+	for {
+		old := atomic.LoadUint64(addr)
+		if old&mask != 0 {
+			return
+		}
+		if atomic.CompareAndSwapUint64(addr, old, old|mask) {
+			return
+		}
+	}
+}
+
+func (cbf *ConcurrentBloomFilter) getBit(i uint32) bool {
+	return atomic.LoadUint64(&cbf.words[i/64])&(uint64(1)<<(i%64)) != 0
+}
+
+// Add adds an element (in byte-array form) to the Bloom Filter. It is
+// safe to call concurrently with Add and Check from other goroutines.
+func (cbf *ConcurrentBloomFilter) Add(e []byte) {
+	h1, h2 := cbf.hasher.Hash128(e)
+	for i := 0; i < cbf.k; i++ {
+		cbf.setBit(cbf.index(h1, h2, i))
+	}
+	atomic.AddInt64(&cbf.n, 1)
+}
+
+// AddBatch adds many elements at once. It computes every element's k bit
+// positions up front, groups them by the word they fall in, and issues a
+// single CAS per touched word instead of one per bit set, which
+// amortizes atomic overhead across the batch.
+func (cbf *ConcurrentBloomFilter) AddBatch(es [][]byte) {
+	maskByWord := make(map[uint32]uint64, len(es)*cbf.k)
+	for _, e := range es {
+		h1, h2 := cbf.hasher.Hash128(e)
+		for i := 0; i < cbf.k; i++ {
+			idx := cbf.index(h1, h2, i)
+			maskByWord[idx/64] |= uint64(1) << (idx % 64)
+		}
+	}
+
+	for wordIdx, mask := range maskByWord {
+		addr := &cbf.words[wordIdx]
+		for {
+			old := atomic.LoadUint64(addr)
+			if old&mask == mask {
+				break
+			}
+			if atomic.CompareAndSwapUint64(addr, old, old|mask) {
+				break
+			}
+		}
+	}
+	atomic.AddInt64(&cbf.n, int64(len(es)))
+}
+
+// Check checks if an element (in byte-array form) exists in the Bloom
+// Filter. It is safe to call concurrently with Add and Check from other
+// goroutines.
+func (cbf *ConcurrentBloomFilter) Check(x []byte) bool {
+	h1, h2 := cbf.hasher.Hash128(x)
+	for i := 0; i < cbf.k; i++ {
+		if !cbf.getBit(cbf.index(h1, h2, i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// FalsePositiveRate returns the current False Positive Rate of the
+// Bloom Filter.
+func (cbf *ConcurrentBloomFilter) FalsePositiveRate() float64 {
+	n := atomic.LoadInt64(&cbf.n)
+	return math.Pow((1 - math.Exp(-float64(cbf.k)*float64(n)/float64(cbf.m))), float64(cbf.k))
+}
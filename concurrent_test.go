@@ -0,0 +1,131 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentBFBasic(t *testing.T) {
+	cbf := NewConcurrentBloomFilter(4, 1000)
+	d1, d2 := []byte("Hello"), []byte("Jello")
+	cbf.Add(d1)
+
+	if !cbf.Check(d1) {
+		t.Errorf("d1 should be present in the ConcurrentBloomFilter")
+	}
+	if cbf.Check(d2) {
+		t.Errorf("d2 should be absent from the ConcurrentBloomFilter")
+	}
+}
+
+func TestConcurrentBFAddBatch(t *testing.T) {
+	cbf := NewConcurrentBloomFilter(4, 1000)
+	elems := [][]byte{[]byte("Hello"), []byte("World"), []byte("Jello")}
+	cbf.AddBatch(elems)
+
+	for _, e := range elems {
+		if !cbf.Check(e) {
+			t.Errorf("%s should be present after AddBatch", e)
+		}
+	}
+}
+
+func TestConcurrentBFParallelAdd(t *testing.T) {
+	cbf := NewConcurrentBloomFilter(4, 100000)
+	const n = 10000
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < n; i += 8 {
+				buf := make([]byte, 8)
+				binary.PutVarint(buf, int64(i))
+				cbf.Add(buf)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		if !cbf.Check(buf) {
+			t.Errorf("%d should be present after concurrent Add", i)
+		}
+	}
+}
+
+// mutexBloomFilter wraps the plain BloomFilter with a mutex, giving a
+// lock-based baseline to compare ConcurrentBloomFilter's lock-free Add
+// against.
+type mutexBloomFilter struct {
+	mu sync.Mutex
+	bf *BloomFilter
+}
+
+func newMutexBloomFilter(numHashFuncs, bfSize int) *mutexBloomFilter {
+	return &mutexBloomFilter{bf: NewBloomFilter(numHashFuncs, bfSize)}
+}
+
+func (m *mutexBloomFilter) Add(e []byte) {
+	m.mu.Lock()
+	m.bf.Add(e)
+	m.mu.Unlock()
+}
+
+func benchmarkElement(i int) []byte {
+	buf := make([]byte, 8)
+	binary.PutVarint(buf, int64(i))
+	return buf
+}
+
+// benchmarkAtGoroutines drives b.N calls to add split evenly across a
+// fixed number of goroutines, so throughput at different goroutine
+// counts can be compared directly.
+func benchmarkAtGoroutines(b *testing.B, goroutines int, add func(i int)) {
+	var wg sync.WaitGroup
+	b.ResetTimer()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < b.N; i += goroutines {
+				add(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func BenchmarkBloomFilterSingleThreaded(b *testing.B) {
+	bf := NewBloomFilter(4, 1<<20)
+	for i := 0; i < b.N; i++ {
+		bf.Add(benchmarkElement(i))
+	}
+}
+
+func BenchmarkBloomFilterMutex(b *testing.B) {
+	for _, goroutines := range []int{1, 2, 4, 8, 16} {
+		b.Run(strconv.Itoa(goroutines), func(b *testing.B) {
+			mbf := newMutexBloomFilter(4, 1<<20)
+			benchmarkAtGoroutines(b, goroutines, func(i int) {
+				mbf.Add(benchmarkElement(i))
+			})
+		})
+	}
+}
+
+func BenchmarkConcurrentBloomFilter(b *testing.B) {
+	for _, goroutines := range []int{1, 2, 4, 8, 16} {
+		b.Run(strconv.Itoa(goroutines), func(b *testing.B) {
+			cbf := NewConcurrentBloomFilter(4, 1<<20)
+			benchmarkAtGoroutines(b, goroutines, func(i int) {
+				cbf.Add(benchmarkElement(i))
+			})
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// cuckooBucketSize is the number of fingerprint slots per bucket.
+const cuckooBucketSize = 4
+
+// cuckooMaxKicks bounds how many evictions Insert will chase before
+// giving up and reporting the filter full.
+const cuckooMaxKicks = 500
+
+// cuckooFilterMagic identifies a serialized CuckooFilter.
+const cuckooFilterMagic uint32 = 0xC0CC0075
+
+// cuckooFilterVersion is bumped whenever the on-disk format changes.
+const cuckooFilterVersion uint8 = 1
+
+// cuckooBucket holds cuckooBucketSize fingerprints. A zero fingerprint
+// marks an empty slot, so real fingerprints are never allowed to be 0.
+type cuckooBucket [cuckooBucketSize]uint8
+
+// CuckooFilter is an alternative to CountingBloomFilter that supports
+// deletion at roughly 1 byte per element at a 3% false positive rate,
+// instead of CountingBloomFilter's 8 bytes per slot. Every element is
+// stored as an 8-bit fingerprint in one of two candidate buckets,
+// cuckoo-hashing style: when both candidate buckets are full, Insert
+// evicts a random occupant to its own alternate bucket to make room.
+type CuckooFilter struct {
+	buckets    []cuckooBucket
+	numBuckets int
+	count      int
+	hasher     Hasher
+}
+
+// NewCuckooFilter returns a new CuckooFilter with at least numBuckets
+// buckets of 4 fingerprints each (numBuckets is rounded up to a power
+// of two, which the two-candidate-bucket scheme requires).
+func NewCuckooFilter(numBuckets int) *CuckooFilter {
+	return NewCuckooFilterWithHasher(numBuckets, fnvHasher{})
+}
+
+// NewCuckooFilterWithHasher is like NewCuckooFilter, but lets the
+// caller supply a custom Hasher instead of the default FNV-based one.
+func NewCuckooFilterWithHasher(numBuckets int, h Hasher) *CuckooFilter {
+	n := nextPowerOfTwo(numBuckets)
+	return &CuckooFilter{
+		buckets:    make([]cuckooBucket, n),
+		numBuckets: n,
+		hasher:     h,
+	}
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fingerprint derives an element's bucket index and 8-bit fingerprint.
+// The fingerprint is never 0, since that value marks an empty slot.
+func (cf *CuckooFilter) fingerprint(e []byte) (i int, fp uint8) {
+	h1, h2 := cf.hasher.Hash128(e)
+	i = int(h1 % uint64(cf.numBuckets))
+	fp = uint8(h2)
+	if fp == 0 {
+		fp = 1
+	}
+	return i, fp
+}
+
+// altIndex returns the other candidate bucket for a fingerprint, given
+// one of its buckets. Since numBuckets is a power of two and altIndex
+// is its own inverse (i XOR mask XOR mask == i), this works from either
+// bucket without re-hashing the original element.
+func (cf *CuckooFilter) altIndex(i int, fp uint8) int {
+	h, _ := cf.hasher.Hash128([]byte{fp})
+	return i ^ int(h%uint64(cf.numBuckets))
+}
+
+func (cf *CuckooFilter) insertIntoBucket(i int, fp uint8) bool {
+	b := &cf.buckets[i]
+	for s := 0; s < cuckooBucketSize; s++ {
+		if b[s] == 0 {
+			b[s] = fp
+			return true
+		}
+	}
+	return false
+}
+
+func (cf *CuckooFilter) bucketHas(i int, fp uint8) bool {
+	b := &cf.buckets[i]
+	for s := 0; s < cuckooBucketSize; s++ {
+		if b[s] == fp {
+			return true
+		}
+	}
+	return false
+}
+
+func (cf *CuckooFilter) deleteFromBucket(i int, fp uint8) bool {
+	b := &cf.buckets[i]
+	for s := 0; s < cuckooBucketSize; s++ {
+		if b[s] == fp {
+			b[s] = 0
+			return true
+		}
+	}
+	return false
+}
+
+// cuckooEviction records one bucket/slot overwritten by a kick, and
+// what it held before, so Insert can undo a failed kick chain.
+type cuckooEviction struct {
+	index, slot int
+	evicted     uint8
+}
+
+// Insert adds an element to the filter, returning false if it could not
+// find room within cuckooMaxKicks evictions (the filter is too full).
+// A failed Insert leaves the filter exactly as it found it: every
+// fingerprint displaced while chasing an empty slot is put back before
+// returning, so an Insert that returns false never loses an element
+// that was already present.
+func (cf *CuckooFilter) Insert(e []byte) bool {
+	i1, fp := cf.fingerprint(e)
+	i2 := cf.altIndex(i1, fp)
+
+	if cf.insertIntoBucket(i1, fp) || cf.insertIntoBucket(i2, fp) {
+		cf.count++
+		return true
+	}
+
+	i := i1
+	if rand.Intn(2) == 1 {
+		i = i2
+	}
+
+	var evictions []cuckooEviction
+	for kick := 0; kick < cuckooMaxKicks; kick++ {
+		slot := rand.Intn(cuckooBucketSize)
+		evicted := cf.buckets[i][slot]
+		cf.buckets[i][slot] = fp
+		evictions = append(evictions, cuckooEviction{i, slot, evicted})
+
+		fp = evicted
+		i = cf.altIndex(i, fp)
+		if cf.insertIntoBucket(i, fp) {
+			cf.count++
+			return true
+		}
+	}
+
+	for j := len(evictions) - 1; j >= 0; j-- {
+		ev := evictions[j]
+		cf.buckets[ev.index][ev.slot] = ev.evicted
+	}
+	return false
+}
+
+// Contains reports whether an element may be in the filter. Like a
+// bloom filter, it can return false positives but never false
+// negatives for elements that were successfully Inserted and not since
+// Deleted.
+func (cf *CuckooFilter) Contains(e []byte) bool {
+	i1, fp := cf.fingerprint(e)
+	return cf.bucketHas(i1, fp) || cf.bucketHas(cf.altIndex(i1, fp), fp)
+}
+
+// Delete removes an element from the filter, returning false if its
+// fingerprint was not found in either candidate bucket. Unlike
+// CountingBloomFilter, Delete will never accidentally remove a
+// different element's bits, but it can still remove a fingerprint that
+// collided with one belonging to an element that was never inserted.
+func (cf *CuckooFilter) Delete(e []byte) bool {
+	i1, fp := cf.fingerprint(e)
+	if cf.deleteFromBucket(i1, fp) {
+		cf.count--
+		return true
+	}
+	if cf.deleteFromBucket(cf.altIndex(i1, fp), fp) {
+		cf.count--
+		return true
+	}
+	return false
+}
+
+// Count returns the number of elements currently in the filter.
+func (cf *CuckooFilter) Count() int {
+	return cf.count
+}
+
+// LoadFactor returns the fraction of fingerprint slots currently
+// occupied, between 0 and 1.
+func (cf *CuckooFilter) LoadFactor() float64 {
+	return float64(cf.count) / float64(cf.numBuckets*cuckooBucketSize)
+}
+
+// MarshalBinary encodes cf into a portable binary form suitable for
+// writing to disk or sending over the network. See WriteTo for the
+// format.
+func (cf *CuckooFilter) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if _, err := cf.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a CuckooFilter previously encoded with
+// MarshalBinary or WriteTo, replacing cf's contents.
+func (cf *CuckooFilter) UnmarshalBinary(data []byte) error {
+	_, err := cf.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// WriteTo writes cf to w in a portable binary format: a magic number,
+// version byte, and hasher ID, followed by numBuckets and count (both
+// little-endian uint32), and finally the raw bucket bytes (4 fingerprint
+// bytes per bucket). It satisfies io.WriterTo.
+func (cf *CuckooFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 4+1+1+4+4)
+	binary.LittleEndian.PutUint32(header[0:4], cuckooFilterMagic)
+	header[4] = cuckooFilterVersion
+	header[5] = cf.hasher.HasherID()
+	binary.LittleEndian.PutUint32(header[6:10], uint32(cf.numBuckets))
+	binary.LittleEndian.PutUint32(header[10:14], uint32(cf.count))
+
+	written, err := w.Write(header)
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	for _, b := range cf.buckets {
+		n, err := w.Write(b[:])
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom reads a CuckooFilter previously written by WriteTo from r,
+// replacing cf's contents. It satisfies io.ReaderFrom.
+func (cf *CuckooFilter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, 4+1+1+4+4)
+	read, err := io.ReadFull(r, header)
+	total := int64(read)
+	if err != nil {
+		return total, err
+	}
+
+	magic := binary.LittleEndian.Uint32(header[0:4])
+	if magic != cuckooFilterMagic {
+		return total, errors.New("bloomfilter: data is not a serialized CuckooFilter")
+	}
+	if version := header[4]; version != cuckooFilterVersion {
+		return total, fmt.Errorf("bloomfilter: unsupported serialization version %d", version)
+	}
+
+	newHasher, ok := hasherRegistry[header[5]]
+	if !ok {
+		return total, fmt.Errorf("bloomfilter: unregistered hasher id %d", header[5])
+	}
+
+	cf.numBuckets = int(binary.LittleEndian.Uint32(header[6:10]))
+	cf.count = int(binary.LittleEndian.Uint32(header[10:14]))
+	cf.hasher = newHasher()
+	cf.buckets = make([]cuckooBucket, cf.numBuckets)
+
+	for i := range cf.buckets {
+		n, err := io.ReadFull(r, cf.buckets[i][:])
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
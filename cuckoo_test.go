@@ -0,0 +1,108 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestCuckooFilterBasic(t *testing.T) {
+	cf := NewCuckooFilter(64)
+	d1, d2 := []byte("Hello"), []byte("Jello")
+
+	if !cf.Insert(d1) {
+		t.Fatalf("Insert should succeed on a mostly-empty filter")
+	}
+	if !cf.Contains(d1) {
+		t.Errorf("d1 should be present in the CuckooFilter")
+	}
+	if cf.Contains(d2) {
+		t.Errorf("d2 should be absent from the CuckooFilter")
+	}
+
+	if !cf.Delete(d1) {
+		t.Errorf("Delete should succeed for an inserted element")
+	}
+	if cf.Contains(d1) {
+		t.Errorf("d1 should be absent from the CuckooFilter after deletion")
+	}
+	if cf.Delete(d1) {
+		t.Errorf("Delete should fail the second time for an already-deleted element")
+	}
+}
+
+func TestCuckooFilterCountAndLoadFactor(t *testing.T) {
+	cf := NewCuckooFilter(256)
+
+	for i := 0; i < 500; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		if !cf.Insert(buf) {
+			t.Fatalf("Insert %d failed: filter should have room", i)
+		}
+	}
+
+	if cf.Count() != 500 {
+		t.Errorf("expected Count() == 500, got %d", cf.Count())
+	}
+	if lf := cf.LoadFactor(); lf <= 0 || lf > 1 {
+		t.Errorf("expected LoadFactor() in (0, 1], got %f", lf)
+	}
+
+	for i := 0; i < 500; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		if !cf.Contains(buf) {
+			t.Errorf("%d should be present in the CuckooFilter", i)
+		}
+	}
+}
+
+func TestCuckooFilterFailedInsertKeepsExistingElements(t *testing.T) {
+	cf := NewCuckooFilter(8) // rounds up to 8 buckets * 4 slots = 32 slots
+
+	var inserted [][]byte
+	for i := 0; i < 64; i++ {
+		buf := make([]byte, 8)
+		binary.PutVarint(buf, int64(i))
+		if cf.Insert(buf) {
+			inserted = append(inserted, buf)
+		} else {
+			break
+		}
+	}
+
+	if len(inserted) >= 64 {
+		t.Fatalf("expected Insert to eventually report the filter full")
+	}
+	if cf.Count() != len(inserted) {
+		t.Errorf("expected Count() == %d, got %d", len(inserted), cf.Count())
+	}
+	for _, e := range inserted {
+		if !cf.Contains(e) {
+			t.Errorf("%v should still be present after a later Insert failed", e)
+		}
+	}
+}
+
+func TestCuckooFilterSerializeRoundTrip(t *testing.T) {
+	cf := NewCuckooFilter(64)
+	cf.Insert([]byte("Hello"))
+	cf.Insert([]byte("World"))
+
+	data, err := cf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	restored := new(CuckooFilter)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+
+	if restored.Count() != cf.Count() {
+		t.Errorf("expected restored Count() == %d, got %d", cf.Count(), restored.Count())
+	}
+	if !restored.Contains([]byte("Hello")) || !restored.Contains([]byte("World")) {
+		t.Errorf("restored filter should still contain the original elements")
+	}
+}
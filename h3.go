@@ -0,0 +1,102 @@
+package bloomfilter
+
+import (
+	"math"
+	"math/rand"
+)
+
+// h3Bits is the width, in bits, of the fixed-size word H3BloomFilter
+// reduces every input to before hashing.
+const h3Bits = 64
+
+// H3BloomFilter is a drop-in alternative to BloomFilter that computes
+// its k hashes using the H3 family of hash functions (Carter-Wegman
+// universal hashing over GF(2)) instead of double hashing. H3 hashes
+// are provably 3-independent, which avoids the false-positive skew
+// double hashing can suffer when its two seed hashes happen to be
+// correlated.
+type H3BloomFilter struct {
+	bitmap *bitset          // The bloom-filter bitmap, packed into 64-bit words
+	q      [][h3Bits]uint64 // k x h3Bits matrix of random hash coefficients
+	k      int              // Number of hash functions
+	n      int              // Number of elements in the filter
+	m      int              // Size of the bloom filter
+	hasher Hasher           // Reduces arbitrary-length input to a fixed h3Bits word
+}
+
+// NewH3BloomFilter returns a new H3BloomFilter, if you pass the number
+// of Hash Functions to use and the maximum size of the Bloom Filter. Its
+// hash coefficient matrix is seeded randomly, so two filters created
+// this way hash inputs differently.
+func NewH3BloomFilter(numHashFuncs, bfSize int) *H3BloomFilter {
+	return newH3BloomFilter(numHashFuncs, bfSize, rand.New(rand.NewSource(rand.Int63())))
+}
+
+// NewH3BloomFilterWithSeed is like NewH3BloomFilter, but derives the
+// hash coefficient matrix from seed, so the same seed always produces
+// filters that hash inputs identically.
+func NewH3BloomFilterWithSeed(numHashFuncs, bfSize int, seed int64) *H3BloomFilter {
+	return newH3BloomFilter(numHashFuncs, bfSize, rand.New(rand.NewSource(seed)))
+}
+
+func newH3BloomFilter(numHashFuncs, bfSize int, r *rand.Rand) *H3BloomFilter {
+	q := make([][h3Bits]uint64, numHashFuncs)
+	for i := range q {
+		for j := 0; j < h3Bits; j++ {
+			q[i][j] = r.Uint64()
+		}
+	}
+	return &H3BloomFilter{
+		bitmap: newBitset(uint(bfSize)),
+		q:      q,
+		k:      numHashFuncs,
+		m:      bfSize,
+		hasher: fnvHasher{},
+	}
+}
+
+// word reduces an arbitrary-length input to the fixed h3Bits-wide word
+// H3 hashing operates over.
+func (hf *H3BloomFilter) word(e []byte) uint64 {
+	x, _ := hf.hasher.Hash128(e)
+	return x
+}
+
+// index computes the i-th H3 hash of x: the XOR of row i of Q over every
+// bit position j where x has bit j set.
+func (hf *H3BloomFilter) index(i int, x uint64) uint32 {
+	var h uint64
+	row := &hf.q[i]
+	for j := 0; j < h3Bits; j++ {
+		if x&(1<<uint(j)) != 0 {
+			h ^= row[j]
+		}
+	}
+	return uint32(h % uint64(hf.m))
+}
+
+// Adds an element (in byte-array form) to the Bloom Filter
+func (hf *H3BloomFilter) Add(e []byte) {
+	x := hf.word(e)
+	for i := 0; i < hf.k; i++ {
+		hf.bitmap.set(uint(hf.index(i, x)))
+	}
+	hf.n++
+}
+
+// Checks if an element (in byte-array form) exists in the Bloom Filter
+func (hf *H3BloomFilter) Check(e []byte) bool {
+	x := hf.word(e)
+	for i := 0; i < hf.k; i++ {
+		if !hf.bitmap.get(uint(hf.index(i, x))) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the current False Positive Rate of the Bloom Filter
+func (hf *H3BloomFilter) FalsePositiveRate() float64 {
+	return math.Pow((1 - math.Exp(-float64(hf.k*hf.n)/
+		float64(hf.m))), float64(hf.k))
+}
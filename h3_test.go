@@ -0,0 +1,33 @@
+package bloomfilter
+
+import "testing"
+
+func TestH3BFBasic(t *testing.T) {
+	hf := NewH3BloomFilterWithSeed(4, 1000, 42)
+	d1, d2 := []byte("Hello"), []byte("Jello")
+	hf.Add(d1)
+
+	if !hf.Check(d1) {
+		t.Errorf("d1 should be present in the H3BloomFilter")
+	}
+	if hf.Check(d2) {
+		t.Errorf("d2 should be absent from the H3BloomFilter")
+	}
+}
+
+func TestH3BFSeedIsDeterministic(t *testing.T) {
+	a := NewH3BloomFilterWithSeed(4, 1000, 7)
+	b := NewH3BloomFilterWithSeed(4, 1000, 7)
+	a.Add([]byte("Hello"))
+	b.Add([]byte("Hello"))
+
+	if !b.Check([]byte("Hello")) {
+		t.Errorf("same seed should produce the same hash coefficients")
+	}
+	for i := 0; i < len(a.bitmap.words); i++ {
+		if a.bitmap.words[i] != b.bitmap.words[i] {
+			t.Errorf("filters built from the same seed should set identical bits")
+			break
+		}
+	}
+}
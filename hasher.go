@@ -0,0 +1,63 @@
+package bloomfilter
+
+import "hash/fnv"
+
+// hasherIDFNV identifies the built-in FNV-based Hasher for
+// serialization; see RegisterHasher for how custom hashers get their
+// own ID.
+const hasherIDFNV uint8 = 0
+
+// Hasher computes two independent 64-bit hashes of an element. A filter
+// combines them via Kirsch-Mitzenmacher double hashing to derive its k
+// bit indices, so callers who want a keyed hash (e.g. SipHash with a
+// random key, to resist hash-flooding) or a faster non-cryptographic
+// hash (xxhash, murmur3) can plug it in instead of the built-in FNV
+// hasher.
+type Hasher interface {
+	// Hash128 returns two 64-bit hashes of b. Implementations should
+	// make a reasonable effort at making h1 and h2 independent of each
+	// other; deriving both from the same short hash makes double
+	// hashing degenerate.
+	Hash128(b []byte) (h1, h2 uint64)
+
+	// HasherID returns a small integer identifying this Hasher's type,
+	// persisted alongside a serialized filter so ReadFrom/
+	// UnmarshalBinary can restore the same Hasher. Custom
+	// implementations must call RegisterHasher with a unique ID for
+	// their filters to be deserializable.
+	HasherID() uint8
+}
+
+// hasherRegistry maps a HasherID to a factory that reconstructs it,
+// used by BloomFilter.ReadFrom to restore the hasher a filter was
+// serialized with.
+var hasherRegistry = map[uint8]func() Hasher{
+	hasherIDFNV: func() Hasher { return fnvHasher{} },
+}
+
+// RegisterHasher makes a custom Hasher implementation resolvable by id
+// when deserializing a filter that was created with it. id must not
+// collide with hasherIDFNV or another registered hasher's id.
+func RegisterHasher(id uint8, factory func() Hasher) {
+	hasherRegistry[id] = factory
+}
+
+// fnvHasher is the default Hasher, built from two independently-seeded
+// FNV-1a states. It is not a keyed hash, so it is not suitable for
+// adversarial input.
+type fnvHasher struct{}
+
+func (fnvHasher) Hash128(b []byte) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write(b)
+
+	h2 := fnv.New64a()
+	// Prefix with a fixed byte so h2 is not a trivial function of h1
+	// for short inputs.
+	h2.Write([]byte{0xff})
+	h2.Write(b)
+
+	return h1.Sum64(), h2.Sum64()
+}
+
+func (fnvHasher) HasherID() uint8 { return hasherIDFNV }